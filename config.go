@@ -0,0 +1,189 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+	"gopkg.in/yaml.v3"
+)
+
+// Config controls how the HTTP server listens: address, timeouts, and
+// optional TLS. Precedence, highest first: explicit flag, env var, config
+// file, default.
+type Config struct {
+	Addr              string        `yaml:"addr"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+	TLS               TLSConfig     `yaml:"tls"`
+}
+
+// TLSConfig switches the server to ListenAndServeTLS when CertFile/KeyFile
+// are set, or to autocert when AutoCertDomains is set. Leaving all three
+// empty serves plain HTTP.
+type TLSConfig struct {
+	CertFile        string   `yaml:"cert_file"`
+	KeyFile         string   `yaml:"key_file"`
+	AutoCertDomains []string `yaml:"autocert_domains"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		Addr:              ":8080",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      15 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		ShutdownTimeout:   10 * time.Second,
+	}
+}
+
+var (
+	configFlag            = flag.String("config", "config.yaml", "path to a YAML config file")
+	addrFlag              = flag.String("addr", "", "listen address (overrides config/env/default)")
+	readHeaderTimeoutFlag = flag.Duration("read-header-timeout", 0, "http read header timeout")
+	readTimeoutFlag       = flag.Duration("read-timeout", 0, "http read timeout")
+	writeTimeoutFlag      = flag.Duration("write-timeout", 0, "http write timeout")
+	idleTimeoutFlag       = flag.Duration("idle-timeout", 0, "http idle timeout")
+	shutdownTimeoutFlag   = flag.Duration("shutdown-timeout", 0, "graceful shutdown timeout")
+	tlsCertFlag           = flag.String("tls-cert", "", "TLS certificate file")
+	tlsKeyFlag            = flag.String("tls-key", "", "TLS key file")
+)
+
+// loadConfig merges the default config, the optional file at -config, the
+// environment, and explicitly-set flags, in that precedence order.
+func loadConfig() Config {
+	cfg := defaultConfig()
+
+	raw, err := os.ReadFile(*configFlag)
+	switch {
+	case err == nil:
+		var fileCfg Config
+		if err := yaml.Unmarshal(raw, &fileCfg); err != nil {
+			logger.Warn("config: failed to parse file, ignoring", "path", *configFlag, "error", err)
+		} else {
+			cfg = mergeConfig(cfg, fileCfg)
+		}
+	case configFlagSet():
+		// Only warn when the user explicitly pointed -config somewhere;
+		// the default config.yaml not existing is expected and silent.
+		logger.Warn("config: failed to read file", "path", *configFlag, "error", err)
+	}
+
+	cfg = mergeConfig(cfg, configFromEnv())
+	cfg = mergeConfig(cfg, configFromFlags())
+
+	return cfg
+}
+
+// configFlagSet reports whether -config was passed explicitly, as opposed
+// to defaulting to "config.yaml".
+func configFlagSet() bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			set = true
+		}
+	})
+	return set
+}
+
+// mergeConfig overlays every non-zero field of override onto base.
+func mergeConfig(base, override Config) Config {
+	if override.Addr != "" {
+		base.Addr = override.Addr
+	}
+	if override.ReadHeaderTimeout != 0 {
+		base.ReadHeaderTimeout = override.ReadHeaderTimeout
+	}
+	if override.ReadTimeout != 0 {
+		base.ReadTimeout = override.ReadTimeout
+	}
+	if override.WriteTimeout != 0 {
+		base.WriteTimeout = override.WriteTimeout
+	}
+	if override.IdleTimeout != 0 {
+		base.IdleTimeout = override.IdleTimeout
+	}
+	if override.ShutdownTimeout != 0 {
+		base.ShutdownTimeout = override.ShutdownTimeout
+	}
+	if override.TLS.CertFile != "" {
+		base.TLS.CertFile = override.TLS.CertFile
+	}
+	if override.TLS.KeyFile != "" {
+		base.TLS.KeyFile = override.TLS.KeyFile
+	}
+	if len(override.TLS.AutoCertDomains) > 0 {
+		base.TLS.AutoCertDomains = override.TLS.AutoCertDomains
+	}
+	return base
+}
+
+func configFromEnv() Config {
+	var cfg Config
+	cfg.Addr = os.Getenv("ADDR")
+	cfg.ReadHeaderTimeout = envDuration("READ_HEADER_TIMEOUT")
+	cfg.ReadTimeout = envDuration("READ_TIMEOUT")
+	cfg.WriteTimeout = envDuration("WRITE_TIMEOUT")
+	cfg.IdleTimeout = envDuration("IDLE_TIMEOUT")
+	cfg.ShutdownTimeout = envDuration("SHUTDOWN_TIMEOUT")
+	cfg.TLS.CertFile = os.Getenv("TLS_CERT_FILE")
+	cfg.TLS.KeyFile = os.Getenv("TLS_KEY_FILE")
+	if domains := os.Getenv("TLS_AUTOCERT_DOMAINS"); domains != "" {
+		cfg.TLS.AutoCertDomains = strings.Split(domains, ",")
+	}
+	return cfg
+}
+
+func envDuration(key string) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		logger.Warn("config: invalid duration in env, ignoring", "key", key, "value", v)
+		return 0
+	}
+	return d
+}
+
+// serverListenFunc picks the right listen strategy for tlsCfg and returns
+// it as a closure gracefulShutdown can call: plain HTTP, static cert/key
+// TLS, or autocert when a domain list is configured.
+func serverListenFunc(server *http.Server, tlsCfg TLSConfig) func() error {
+	switch {
+	case len(tlsCfg.AutoCertDomains) > 0:
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(tlsCfg.AutoCertDomains...),
+			Cache:      autocert.DirCache("certs"),
+		}
+		server.TLSConfig = manager.TLSConfig()
+		return func() error { return server.ListenAndServeTLS("", "") }
+	case tlsCfg.CertFile != "" && tlsCfg.KeyFile != "":
+		return func() error { return server.ListenAndServeTLS(tlsCfg.CertFile, tlsCfg.KeyFile) }
+	default:
+		return func() error { return server.ListenAndServe() }
+	}
+}
+
+func configFromFlags() Config {
+	var cfg Config
+	cfg.Addr = *addrFlag
+	cfg.ReadHeaderTimeout = *readHeaderTimeoutFlag
+	cfg.ReadTimeout = *readTimeoutFlag
+	cfg.WriteTimeout = *writeTimeoutFlag
+	cfg.IdleTimeout = *idleTimeoutFlag
+	cfg.ShutdownTimeout = *shutdownTimeoutFlag
+	cfg.TLS.CertFile = *tlsCertFlag
+	cfg.TLS.KeyFile = *tlsKeyFlag
+	return cfg
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// devServer backs the -dev flag: it re-parses static/index.html from disk
+// whenever fsnotify reports a write or create in dir and notifies connected browsers over
+// the /.dev/reload SSE endpoint so they can reload themselves.
+type devServer struct {
+	dir string
+
+	mu       sync.RWMutex
+	tpl      *template.Template
+	parseErr error
+
+	clientsMu sync.Mutex
+	clients   map[chan struct{}]struct{}
+}
+
+func newDevServer(dir string) *devServer {
+	d := &devServer{
+		dir:     dir,
+		clients: make(map[chan struct{}]struct{}),
+	}
+	d.reload()
+	return d
+}
+
+func (d *devServer) reload() {
+	tpl, err := template.ParseFiles(filepath.Join(d.dir, "index.html"))
+
+	d.mu.Lock()
+	d.tpl, d.parseErr = tpl, err
+	d.mu.Unlock()
+}
+
+// Template returns the most recently parsed template, or the parse error
+// from the last attempt.
+func (d *devServer) Template() (*template.Template, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.tpl, d.parseErr
+}
+
+// watch blocks, reparsing the template and notifying clients on every
+// write or create event in dir, until ctx is cancelled. Other events
+// (remove, rename, chmod) are ignored, and subdirectories are not watched.
+func (d *devServer) watch(ctx context.Context, logger *slog.Logger) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("dev: create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(d.dir); err != nil {
+		return fmt.Errorf("dev: watch %s: %w", d.dir, err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			logger.Info("dev: reparsing template", "file", event.Name)
+			d.reload()
+			d.broadcast()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Error("dev: watcher error", "error", err)
+		}
+	}
+}
+
+func (d *devServer) broadcast() {
+	d.clientsMu.Lock()
+	defer d.clientsMu.Unlock()
+	for c := range d.clients {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// handleReload is the /.dev/reload SSE endpoint the injected dev script
+// subscribes to.
+func (d *devServer) handleReload(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := make(chan struct{}, 1)
+	d.clientsMu.Lock()
+	d.clients[ch] = struct{}{}
+	d.clientsMu.Unlock()
+	defer func() {
+		d.clientsMu.Lock()
+		delete(d.clients, ch)
+		d.clientsMu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			fmt.Fprint(w, "data: reload\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// devErrorPage renders a styled page for a template parse error instead of
+// crashing the dev server.
+func devErrorPage(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html><head><title>template error</title>
+<style>body{font-family:monospace;background:#2b0000;color:#ffb3b3;margin:2rem}pre{white-space:pre-wrap}</style>
+</head><body><h1>template parse error</h1><pre>%s</pre></body></html>`, template.HTMLEscapeString(err.Error()))
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// newLogger builds the process-wide slog.Logger from LOG_FORMAT
+// (logfmt|json|console, default logfmt) and LOG_LEVEL
+// (debug|info|warn|error, default info).
+func newLogger() *slog.Logger {
+	opts := &slog.HandlerOptions{Level: parseLogLevel(os.Getenv("LOG_LEVEL"))}
+
+	var handler slog.Handler
+	switch strings.ToLower(os.Getenv("LOG_FORMAT")) {
+	case "json":
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	case "console":
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	default:
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+
+	return slog.New(handler)
+}
+
+func parseLogLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// responseWriter wraps http.ResponseWriter to capture the status code and
+// bytes written for the request logging middleware.
+type responseWriter struct {
+	http.ResponseWriter
+	status       int
+	bytesWritten int
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytesWritten += n
+	return n, err
+}
+
+// Flush forwards to the underlying ResponseWriter's http.Flusher so SSE
+// handlers (handleStatsStream, devServer.handleReload, ...) still work
+// when wrapped by loggingMiddleware.
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// loggingMiddleware logs one structured line per request: method, path,
+// status, bytes written, duration, and remote addr.
+func loggingMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+
+		atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+
+		next.ServeHTTP(rw, r)
+
+		logger.Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rw.status,
+			"bytes", rw.bytesWritten,
+			"duration", time.Since(start),
+			"remote_addr", r.RemoteAddr,
+		)
+	})
+}
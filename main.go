@@ -2,33 +2,69 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"embed"
+	"encoding/json"
+	"flag"
 	"html/template"
 	"io/fs"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
-	"runtime/pprof"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/riskiwah/nothingtodo/scanner"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
 )
 
-// todo! add -ldflags (build time)
-// references: https://blog.alexellis.io/inject-build-time-vars-golang/
+// scannerConfigPath is where the service-health scanner reads its list of
+// probes from. See scanner.LoadConfig.
+const scannerConfigPath = "scanner.yaml"
+
+// devFlag enables live-reload dev mode: static/ is read from disk instead
+// of the embedded FS, the template is reparsed on change, and connected
+// browsers are told to reload over /.dev/reload.
+var devFlag = flag.Bool("dev", false, "serve static/ from disk and live-reload on change")
+
+// commit, buildTimestamp, and year are set via -ldflags at build time, see
+// version.go and the Makefile.
 var (
 	//go:embed all:static
 	staticFiles    embed.FS
 	commit         string
 	buildTimestamp string
 	year           string
+
+	// svcScanner is nil until main wires up a scanner config; renderTemplate
+	// and handleStatusJSON treat a nil scanner as "no services configured".
+	svcScanner *scanner.Scanner
+
+	// logger is the process-wide structured logger, configured in main via
+	// LOG_FORMAT/LOG_LEVEL.
+	logger *slog.Logger
+
+	// inFlight tracks requests currently being served, so shutdown can log
+	// how many were still in flight when it began.
+	inFlight int64
 )
 
 type TemplateData struct {
-	Footer Footer
-	Stats  Stats
+	Footer   Footer
+	Stats    Stats
+	Services []scanner.ServiceStatus
+	DevMode  bool
 }
 
 type Footer struct {
@@ -44,17 +80,49 @@ type Stats struct {
 	Uptime        string
 	KernelVersion string
 	KernelArch    string
+
+	CPUPercent float64
+	LoadAvg1   float64
+	LoadAvg5   float64
+	LoadAvg15  float64
+	Mem        MemStats
+	Swap       MemStats
+	Disks      []DiskStats
 }
 
-// todo! add dynamic path after /pprof/{goroutine,heap,allocs,etcetc}
-// with switch case maybe (?)
-// type profiles string
+// MemStats covers both physical and swap memory; gopsutil reports both the
+// same way so one struct serves both.
+type MemStats struct {
+	Total       uint64
+	Used        uint64
+	UsedPercent float64
+}
 
-func renderTemplate(stats Stats) (TemplateData, error) {
-	stats, err := getStatus()
-	if err != nil {
-		return TemplateData{}, err
+type DiskStats struct {
+	Mountpoint  string
+	Total       uint64
+	Used        uint64
+	UsedPercent float64
+}
+
+// pprofToken, when set via the PPROF_TOKEN env var, gates access to
+// /debug/pprof/ behind a bearer token. An empty value leaves it open,
+// matching the previous unauthenticated /pprof behavior.
+var pprofToken = os.Getenv("PPROF_TOKEN")
+
+// profileRuntimeKnobs applies the block/mutex profile rate env vars so
+// those profiles actually collect samples. Called once from main.
+func profileRuntimeKnobs() {
+	if rate, err := strconv.Atoi(os.Getenv("PPROF_BLOCK_RATE")); err == nil && rate > 0 {
+		runtime.SetBlockProfileRate(rate)
 	}
+	if frac, err := strconv.Atoi(os.Getenv("PPROF_MUTEX_FRACTION")); err == nil && frac > 0 {
+		runtime.SetMutexProfileFraction(frac)
+	}
+}
+
+func renderTemplate(stats Stats) (TemplateData, error) {
+	stats = cachedStats()
 
 	footer := Footer{
 		BuildTimestamp: buildTimestamp,
@@ -66,106 +134,263 @@ func renderTemplate(stats Stats) (TemplateData, error) {
 		Stats:  stats,
 		Footer: footer,
 	}
+	if svcScanner != nil {
+		templateData.Services = svcScanner.Snapshot()
+	}
 
 	return templateData, nil
 }
 
-func handleStatic(tpl *template.Template) http.Handler {
-	rootFS, err := fs.Sub(staticFiles, "static")
-	if err != nil {
-		log.Printf("Error: Failed to load FS: %v", err)
+func handleStatusJSON(w http.ResponseWriter, r *http.Request) {
+	var services []scanner.ServiceStatus
+	if svcScanner != nil {
+		services = svcScanner.Snapshot()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(services); err != nil {
+		logger.Error("failed to encode status JSON", "error", err)
 	}
+}
+
+// handleStatic serves static/index.html at "/" and everything else out of
+// static/. When dev is non-nil it reads from disk instead of the embedded
+// FS and re-fetches the template (reparsed by dev.watch) on every request.
+func handleStatic(tpl *template.Template, dev *devServer) http.Handler {
+	var fileHandler http.Handler
+	if dev != nil {
+		fileHandler = http.FileServer(http.Dir("static"))
+	} else {
+		rootFS, err := fs.Sub(staticFiles, "static")
+		if err != nil {
+			logger.Error("failed to load FS", "error", err)
+		}
+		fileHandler = http.FileServer(http.FS(rootFS))
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/" {
+			activeTpl := tpl
+			if dev != nil {
+				var err error
+				activeTpl, err = dev.Template()
+				if err != nil {
+					devErrorPage(w, err)
+					return
+				}
+			}
+
 			rendering, err := renderTemplate(Stats{})
 			if err != nil {
-				log.Printf("Error rendering template: %v", err)
+				logger.Error("failed to render template", "error", err)
 				http.Error(w, "Internal server error", http.StatusInternalServerError)
 				return
 			}
-			err = tpl.Execute(w, rendering)
-			if err != nil {
-				log.Printf("Error rendering template: %v", err)
+			rendering.DevMode = dev != nil
+
+			if err := activeTpl.Execute(w, rendering); err != nil {
+				logger.Error("failed to render template", "error", err)
 				http.Error(w, "Internal server error", http.StatusInternalServerError)
 			}
 			return
 		}
-		http.FileServer(http.FS(rootFS)).ServeHTTP(w, r)
+		fileHandler.ServeHTTP(w, r)
 	})
 }
 
-func handlePprof(w http.ResponseWriter, req *http.Request) {
-	getPprof := pprof.Lookup("heap")
+// handlePprof mounts the standard net/http/pprof profiles under
+// /debug/pprof/, resolving the requested profile from the URL path.
+func handlePprof(w http.ResponseWriter, r *http.Request) {
+	if pprofToken != "" {
+		auth := r.Header.Get("Authorization")
+		want := "Bearer " + pprofToken
+		if subtle.ConstantTimeCompare([]byte(auth), []byte(want)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
 
-	err := getPprof.WriteTo(w, 1)
-	if err != nil {
-		log.Printf("Error: Failed to write pprof: %v", err)
+	name := strings.TrimPrefix(r.URL.Path, "/debug/pprof/")
+	switch name {
+	case "", "index":
+		pprof.Index(w, r)
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	case "goroutine", "heap", "allocs", "threadcreate", "block", "mutex":
+		pprof.Handler(name).ServeHTTP(w, r)
+	default:
+		pprof.Index(w, r)
 	}
 }
 
 func getStatus() (Stats, error) {
-	stats, err := host.Info()
+	hostStats, err := host.Info()
 	if err != nil {
 		return Stats{}, err
 	}
 
-	convertTime := time.Duration(stats.Uptime) * time.Second
+	convertTime := time.Duration(hostStats.Uptime) * time.Second
 
-	return Stats{
-		Hostname:      stats.Hostname,
+	stats := Stats{
+		Hostname:      hostStats.Hostname,
 		Uptime:        convertTime.String(),
-		KernelVersion: stats.KernelVersion,
-		KernelArch:    stats.KernelArch,
-	}, nil
+		KernelVersion: hostStats.KernelVersion,
+		KernelArch:    hostStats.KernelArch,
+	}
+
+	if percents, err := cpu.Percent(200*time.Millisecond, false); err == nil && len(percents) > 0 {
+		stats.CPUPercent = percents[0]
+	} else if err != nil {
+		logger.Error("failed to read cpu percent", "error", err)
+	}
+
+	if avg, err := load.Avg(); err == nil {
+		stats.LoadAvg1, stats.LoadAvg5, stats.LoadAvg15 = avg.Load1, avg.Load5, avg.Load15
+	} else {
+		logger.Error("failed to read load average", "error", err)
+	}
+
+	if vmem, err := mem.VirtualMemory(); err == nil {
+		stats.Mem = MemStats{Total: vmem.Total, Used: vmem.Used, UsedPercent: vmem.UsedPercent}
+	} else {
+		logger.Error("failed to read virtual memory", "error", err)
+	}
+
+	if swap, err := mem.SwapMemory(); err == nil {
+		stats.Swap = MemStats{Total: swap.Total, Used: swap.Used, UsedPercent: swap.UsedPercent}
+	} else {
+		logger.Error("failed to read swap memory", "error", err)
+	}
+
+	if partitions, err := disk.Partitions(false); err == nil {
+		for _, p := range partitions {
+			usage, err := disk.Usage(p.Mountpoint)
+			if err != nil {
+				continue
+			}
+			stats.Disks = append(stats.Disks, DiskStats{
+				Mountpoint:  p.Mountpoint,
+				Total:       usage.Total,
+				Used:        usage.Used,
+				UsedPercent: usage.UsedPercent,
+			})
+		}
+	} else {
+		logger.Error("failed to list disk partitions", "error", err)
+	}
+
+	return stats, nil
 }
 
-func gracefulShutdown(server *http.Server, timeout time.Duration) error {
+// gracefulShutdown runs serve until ctx is cancelled (by an interrupt or
+// SIGTERM, see main), then shuts the server down with the given timeout.
+func gracefulShutdown(ctx context.Context, server *http.Server, timeout time.Duration, serve func() error) error {
 	done := make(chan error, 1)
 	go func() {
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
-		<-c
-		log.Println("Server is shutting down...")
+		<-ctx.Done()
+		logger.Info("server is shutting down",
+			"timeout", timeout,
+			"in_flight", atomic.LoadInt64(&inFlight),
+		)
 
-		ctx := context.Background()
+		shutdownCtx := context.Background()
 		var cancel context.CancelFunc
 		if timeout > 0 {
-			ctx, cancel = context.WithTimeout(ctx, timeout)
+			shutdownCtx, cancel = context.WithTimeout(shutdownCtx, timeout)
 			defer cancel()
 		}
 
-		done <- server.Shutdown(ctx)
+		done <- server.Shutdown(shutdownCtx)
 	}()
 
-	log.Println("Starting HTTP server...")
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	logger.Info("starting HTTP server", "addr", server.Addr)
+	if err := serve(); err != nil && err != http.ErrServerClosed {
 		return err
 	}
 
-	log.Println("Byeee")
-	return <-done
+	err := <-done
+	logger.Info("server stopped", "error", err)
+	return err
 }
 
 // main function
 func main() {
+	flag.Parse()
+	if *versionFlag {
+		printVersion()
+	}
+	logger = newLogger()
 	router := http.NewServeMux()
 
-	tpl, err := template.ParseFS(staticFiles, "static/index.html")
-	if err != nil {
-		log.Fatalf("Error parsing template: %s", err)
+	// rootCtx is cancelled on SIGINT/SIGTERM and propagated down into
+	// request handlers (via Server.BaseContext) so long-running endpoints
+	// like the pprof trace profile and the SSE streams abort on shutdown
+	// instead of blocking it.
+	rootCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverCfg := loadConfig()
+
+	var tpl *template.Template
+	var dev *devServer
+	if *devFlag {
+		dev = newDevServer("static")
+		if _, err := dev.Template(); err != nil {
+			logger.Warn("dev: initial template parse failed", "error", err)
+		}
+		go func() {
+			if err := dev.watch(rootCtx, logger); err != nil {
+				logger.Error("dev: watcher exited", "error", err)
+			}
+		}()
+	} else {
+		var err error
+		tpl, err = template.ParseFS(staticFiles, "static/index.html")
+		if err != nil {
+			logger.Error("failed to parse template", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if scanCfg, err := scanner.LoadConfig(scannerConfigPath); err != nil {
+		logger.Warn("scanner: not starting", "config", scannerConfigPath, "error", err)
+	} else {
+		svcScanner = scanner.New(scanCfg, logger)
+		go svcScanner.Run(rootCtx)
 	}
 
 	// handler
-	router.Handle("/", handleStatic(tpl))
-	router.HandleFunc("/pprof", handlePprof)
+	profileRuntimeKnobs()
+	go refreshStats(rootCtx, statsStreamInterval())
+
+	router.Handle("/", handleStatic(tpl, dev))
+	router.HandleFunc("/debug/pprof/", handlePprof)
+	router.HandleFunc("/api/status.json", handleStatusJSON)
+	router.HandleFunc("/version", handleVersion)
+	router.HandleFunc("/stats/stream", handleStatsStream)
+	if dev != nil {
+		router.HandleFunc("/.dev/reload", dev.handleReload)
+	}
 
 	server := &http.Server{
-		Addr:        ":8080",
-		Handler:     router,
-		IdleTimeout: 60 * time.Second,
+		Addr:              serverCfg.Addr,
+		Handler:           loggingMiddleware(logger, router),
+		ReadHeaderTimeout: serverCfg.ReadHeaderTimeout,
+		ReadTimeout:       serverCfg.ReadTimeout,
+		WriteTimeout:      serverCfg.WriteTimeout,
+		IdleTimeout:       serverCfg.IdleTimeout,
+		BaseContext:       func(net.Listener) context.Context { return rootCtx },
 	}
 
-	if err := gracefulShutdown(server, 10*time.Second); err != nil {
-		log.Println(err)
+	serve := serverListenFunc(server, serverCfg.TLS)
+
+	if err := gracefulShutdown(rootCtx, server, serverCfg.ShutdownTimeout, serve); err != nil {
+		logger.Error("server exited with error", "error", err)
 	}
 }
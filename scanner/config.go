@@ -0,0 +1,48 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes the set of services the scanner should probe and how
+// often it should probe them.
+type Config struct {
+	Interval time.Duration   `yaml:"interval"`
+	Services []ServiceConfig `yaml:"services"`
+}
+
+// ServiceConfig is a single endpoint to probe.
+type ServiceConfig struct {
+	Name    string        `yaml:"name"`
+	Type    string        `yaml:"type"` // "http" or "tcp"
+	Target  string        `yaml:"target"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// LoadConfig reads and parses a scanner config file in YAML.
+func LoadConfig(path string) (Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("read scanner config: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return Config{}, fmt.Errorf("parse scanner config: %w", err)
+	}
+
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	for i := range cfg.Services {
+		if cfg.Services[i].Timeout <= 0 {
+			cfg.Services[i].Timeout = 5 * time.Second
+		}
+	}
+
+	return cfg, nil
+}
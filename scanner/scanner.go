@@ -0,0 +1,143 @@
+// Package scanner runs periodic up/down probes against a list of
+// configured services and keeps the latest result available for the
+// status page and the /api/status.json endpoint.
+package scanner
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ServiceStatus is the latest known state of a single probed service.
+type ServiceStatus struct {
+	Name        string    `json:"name"`
+	Target      string    `json:"target"`
+	Up          bool      `json:"up"`
+	Latency     string    `json:"latency"`
+	LastChanged time.Time `json:"last_changed"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// Scanner probes a fixed set of services on a timer and keeps the latest
+// results in an atomic.Value so readers never block on a write in
+// progress.
+type Scanner struct {
+	cfg    Config
+	logger *slog.Logger
+	status atomic.Value // []ServiceStatus
+}
+
+// New builds a Scanner for the given config, logging probe failures
+// through logger. Run must be called to start probing; until then
+// Snapshot returns nil.
+func New(cfg Config, logger *slog.Logger) *Scanner {
+	return &Scanner{cfg: cfg, logger: logger}
+}
+
+// Run blocks, probing every configured interval until ctx is cancelled.
+func (s *Scanner) Run(ctx context.Context) {
+	s.scanOnce(ctx)
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.scanOnce(ctx)
+		}
+	}
+}
+
+// Snapshot returns the most recently collected statuses, in config order.
+func (s *Scanner) Snapshot() []ServiceStatus {
+	v, _ := s.status.Load().([]ServiceStatus)
+	return v
+}
+
+func (s *Scanner) scanOnce(ctx context.Context) {
+	prev := make(map[string]ServiceStatus, len(s.cfg.Services))
+	for _, st := range s.Snapshot() {
+		prev[st.Name] = st
+	}
+
+	results := make([]ServiceStatus, len(s.cfg.Services))
+
+	var wg sync.WaitGroup
+	for i, svc := range s.cfg.Services {
+		wg.Add(1)
+		go func(i int, svc ServiceConfig) {
+			defer wg.Done()
+			results[i] = s.probe(ctx, svc, prev[svc.Name])
+		}(i, svc)
+	}
+	wg.Wait()
+
+	s.status.Store(results)
+}
+
+func (s *Scanner) probe(ctx context.Context, svc ServiceConfig, prev ServiceStatus) ServiceStatus {
+	ctx, cancel := context.WithTimeout(ctx, svc.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	up, err := checkService(ctx, svc)
+	latency := time.Since(start)
+
+	status := ServiceStatus{
+		Name:        svc.Name,
+		Target:      svc.Target,
+		Up:          up,
+		Latency:     latency.Round(time.Millisecond).String(),
+		LastChanged: prev.LastChanged,
+	}
+	if err != nil {
+		status.LastError = err.Error()
+	}
+	if prev.Name == "" || prev.Up != up {
+		status.LastChanged = time.Now()
+	}
+	if status.LastChanged.IsZero() {
+		status.LastChanged = time.Now()
+	}
+
+	if err != nil {
+		s.logger.Error("check failed", "service", svc.Name, "target", svc.Target, "error", err)
+	}
+
+	return status
+}
+
+func checkService(ctx context.Context, svc ServiceConfig) (bool, error) {
+	switch svc.Type {
+	case "tcp":
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", svc.Target)
+		if err != nil {
+			return false, err
+		}
+		_ = conn.Close()
+		return true, nil
+	default:
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, svc.Target, nil)
+		if err != nil {
+			return false, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return false, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return false, nil
+		}
+		return true, nil
+	}
+}
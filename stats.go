@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// statsCache holds the latest Stats snapshot so concurrent HTTP requests
+// don't each re-hit the kernel for cpu/mem/disk/load stats.
+var statsCache atomic.Value // Stats
+
+// statsStreamInterval is how often refreshStats recomputes the cached
+// snapshot and /stats/stream emits it, configurable via the
+// STATS_STREAM_INTERVAL env var (seconds).
+func statsStreamInterval() time.Duration {
+	if s := os.Getenv("STATS_STREAM_INTERVAL"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return 5 * time.Second
+}
+
+// refreshStats populates statsCache immediately and then on every tick
+// until ctx is cancelled.
+func refreshStats(ctx context.Context, interval time.Duration) {
+	refreshStatsOnce()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refreshStatsOnce()
+		}
+	}
+}
+
+func refreshStatsOnce() {
+	stats, err := getStatus()
+	if err != nil {
+		logger.Error("failed to refresh stats", "error", err)
+		return
+	}
+	statsCache.Store(stats)
+}
+
+// cachedStats returns the latest cached snapshot, falling back to a
+// synchronous getStatus call if the refresher hasn't populated it yet.
+func cachedStats() Stats {
+	if v, ok := statsCache.Load().(Stats); ok {
+		return v
+	}
+	stats, err := getStatus()
+	if err != nil {
+		logger.Error("failed to get stats", "error", err)
+	}
+	return stats
+}
+
+// handleStatsStream is the /stats/stream SSE endpoint: it emits the cached
+// stats snapshot as JSON every statsStreamInterval.
+func handleStatsStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ticker := time.NewTicker(statsStreamInterval())
+	defer ticker.Stop()
+
+	for {
+		payload, err := json.Marshal(cachedStats())
+		if err != nil {
+			logger.Error("failed to encode stats snapshot", "error", err)
+			return
+		}
+		fmt.Fprintf(w, "data: %s\n\n", payload)
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
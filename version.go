@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+)
+
+// commit, buildTimestamp, and year are normally injected at build time via
+// -ldflags, see the Makefile's "build" target. When they weren't passed
+// (e.g. `go run .` or `go test`), versionInfo falls back to
+// runtime/debug.ReadBuildInfo for the commit.
+var versionFlag = flag.Bool("version", false, "print version info and exit")
+
+// VersionInfo is returned by the /version endpoint and printed by -version.
+type VersionInfo struct {
+	Commit         string `json:"commit"`
+	BuildTimestamp string `json:"build_timestamp"`
+	Year           string `json:"year"`
+	GoVersion      string `json:"go_version"`
+	OS             string `json:"os"`
+	Arch           string `json:"arch"`
+}
+
+func versionInfo() VersionInfo {
+	v := VersionInfo{
+		Commit:         commit,
+		BuildTimestamp: buildTimestamp,
+		Year:           year,
+		GoVersion:      runtime.Version(),
+		OS:             runtime.GOOS,
+		Arch:           runtime.GOARCH,
+	}
+
+	if v.Commit == "" {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range info.Settings {
+				if setting.Key == "vcs.revision" {
+					v.Commit = setting.Value
+				}
+			}
+		}
+	}
+
+	return v
+}
+
+func handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(versionInfo()); err != nil {
+		logger.Error("failed to encode version JSON", "error", err)
+	}
+}
+
+// printVersion implements the -version flag.
+func printVersion() {
+	v := versionInfo()
+	fmt.Printf("commit=%s build_timestamp=%s year=%s go=%s os=%s arch=%s\n",
+		v.Commit, v.BuildTimestamp, v.Year, v.GoVersion, v.OS, v.Arch)
+	os.Exit(0)
+}